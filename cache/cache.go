@@ -17,6 +17,9 @@ package cache
 import (
 	"context"
 	"encoding"
+	"errors"
+	"reflect"
+	"sync"
 	"sync/atomic"
 	"time"
 )
@@ -41,14 +44,61 @@ type Client struct {
 	// WriteTimeout is the max time spent waiting for cache writes to complete (optional - default 3 seconds)
 	WriteTimeout time.Duration
 
+	// SoftTTL is how long an entry is served as fresh before Get starts serving it stale
+	// while asynchronously refreshing it in the background (optional - 0 disables
+	// stale-while-revalidate; entries are always fresh until HardTTL, if any)
+	SoftTTL time.Duration
+
+	// HardTTL is the age at which an entry is treated as if it were never cached, rather
+	// than stale-served (optional - 0 means no client-side hard bound). When set, it must
+	// be >= SoftTTL.
+	HardTTL time.Duration
+
+	// NegativeTTL controls how long a "not found" result (Builder returning
+	// ErrBuilderNotFound) is cached, shielding the Builder from repeated lookups of keys
+	// that don't exist (optional - 0 disables negative caching)
+	NegativeTTL time.Duration
+
 	// track pending cache writes
 	pendingWrites int64
+
+	// guards inflight
+	mu sync.Mutex
+
+	// inflight coalesces concurrent Builder runs for the same key (single-flight), covering
+	// both cache misses and background stale-while-revalidate refreshes
+	inflight map[string]*call
+
+	// now returns the current time; overridable in tests, defaults to time.Now
+	now func() time.Time
+}
+
+// call tracks a single in-flight Builder run shared by all concurrent Get callers for a key
+type call struct {
+	wg      sync.WaitGroup
+	payload []byte
+
+	// err is returned to the leader's own caller (via onCacheMiss) as well as to followers;
+	// it is set when Build itself failed, so neither has a usable value
+	err error
+
+	// followerErr is returned only to followers (via followCall), for failures that happen
+	// after Build already populated the leader's own dest - e.g. a marshal error, which
+	// leaves the leader's result perfectly valid but gives followers nothing to unmarshal
+	followerErr error
 }
 
 // Get attempts to retrieve the value from cache and when it misses will run the builder func to create the value.
 //
 // It will asynchronously update/save the value in the cache on after a successful builder run
 func (c *Client) Get(ctx context.Context, key string, dest BinaryEncoder, builder Builder) error {
+	start := c.getNow()
+	defer func() {
+		if timings, ok := c.getMetrics().(MetricsTimings); ok {
+			timings.ObserveGetDuration(c.getNow().Sub(start))
+		}
+	}()
+
 	bytes, err := c.Storage.Get(ctx, key)
 	if err != nil {
 		if err == ErrCacheMiss {
@@ -61,27 +111,173 @@ func (c *Client) Get(ctx context.Context, key string, dest BinaryEncoder, builde
 		return err
 	}
 
-	return c.onCacheHit(ctx, key, dest, bytes)
+	return c.onCacheHit(ctx, key, dest, bytes, builder)
 }
 
+// onCacheMiss runs builder for key, coalescing concurrent callers so that only one Builder
+// invocation is in-flight per key at a time. Followers wait for the leader's result and
+// unmarshal the shared payload into their own dest.
 func (c *Client) onCacheMiss(ctx context.Context, key string, dest BinaryEncoder, builder Builder) error {
+	c.mu.Lock()
+	if c.inflight == nil {
+		c.inflight = make(map[string]*call)
+	}
+
+	if leader, ok := c.inflight[key]; ok {
+		c.mu.Unlock()
+		return c.followCall(key, dest, leader)
+	}
+
+	thisCall := &call{}
+	thisCall.wg.Add(1)
+	c.inflight[key] = thisCall
+	c.mu.Unlock()
+
+	c.leadCall(ctx, key, dest, builder, thisCall)
+
+	return thisCall.err
+}
+
+// followCall waits for an in-flight Builder run started by another goroutine and unmarshals
+// its shared result into dest
+func (c *Client) followCall(key string, dest BinaryEncoder, leader *call) error {
+	leader.wg.Wait()
+	c.getMetrics().Track(CacheSingleflightShared)
+
+	if leader.err != nil {
+		return leader.err
+	}
+
+	if leader.followerErr != nil {
+		return leader.followerErr
+	}
+
+	return dest.UnmarshalBinary(leader.payload)
+}
+
+// leadCall runs builder, fans the result out via thisCall and kicks off the async cache write
+func (c *Client) leadCall(ctx context.Context, key string, dest BinaryEncoder, builder Builder, thisCall *call) {
+	defer func() {
+		c.mu.Lock()
+		delete(c.inflight, key)
+		c.mu.Unlock()
+
+		thisCall.wg.Done()
+	}()
+
 	err := builder.Build(ctx, key, dest)
 	if err != nil {
+		if errors.Is(err, ErrBuilderNotFound) {
+			thisCall.err = ErrBuilderNotFound
+
+			// a NegativeTTL of 0 disables negative caching, so no tombstone is written
+			if c.NegativeTTL > 0 {
+				atomic.AddInt64(&c.pendingWrites, 1)
+				go c.setNegative(context.Background(), key)
+			}
+			return
+		}
+
 		c.getLogger().Log("cache miss build error. key: '%s' error: %s", key, err)
 		c.getMetrics().Track(CacheLambdaError)
-		return &LambdaError{
+		thisCall.err = &LambdaError{
+			Cause: err,
+		}
+		return
+	}
+
+	payload, err := dest.MarshalBinary()
+	if err != nil {
+		c.getLogger().Log("cache update marshal error. key: '%s' error: %s", key, err)
+		c.getMetrics().Track(CacheMarshalError)
+
+		// the leader's own dest is already populated via Build, so its caller still gets a
+		// nil error; followers have no dest of their own, so give them an explicit error
+		// instead of unmarshaling a nil payload into silent zero-values
+		thisCall.followerErr = &MarshalError{
 			Cause: err,
 		}
+		return
 	}
+	thisCall.payload = payload
 
 	atomic.AddInt64(&c.pendingWrites, 1)
-	go c.Set(context.Background(), key, dest)
+	go c.setBytes(context.Background(), key, payload)
+}
+
+// refreshAsync kicks off a deduplicated background Builder run to refresh a stale entry.
+// Unlike onCacheMiss, failures aren't returned to anyone - the caller that triggered this
+// already received a (stale) value - so they're just logged.
+func (c *Client) refreshAsync(key string, builder Builder, sample BinaryEncoder) {
+	fresh, ok := newLike(sample)
+	if !ok {
+		c.getLogger().Log("cache stale revalidate error. key: '%s' error: %s", key, "dest must be a pointer to support async refresh")
+		return
+	}
+
+	c.mu.Lock()
+	if c.inflight == nil {
+		c.inflight = make(map[string]*call)
+	}
+
+	if _, inFlight := c.inflight[key]; inFlight {
+		// a miss or another refresh is already repopulating this key
+		c.mu.Unlock()
+		return
+	}
+
+	thisCall := &call{}
+	thisCall.wg.Add(1)
+	c.inflight[key] = thisCall
+	c.mu.Unlock()
+
+	go c.leadCall(context.Background(), key, fresh, builder, thisCall)
+}
+
+// newLike constructs a new, zero-valued BinaryEncoder of the same concrete type as sample,
+// so an async refresh has somewhere to Build into without racing the caller's own dest
+func newLike(sample BinaryEncoder) (BinaryEncoder, bool) {
+	v := reflect.ValueOf(sample)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return nil, false
+	}
+
+	fresh, ok := reflect.New(v.Type().Elem()).Interface().(BinaryEncoder)
+	return fresh, ok
+}
+
+func (c *Client) onCacheHit(ctx context.Context, key string, dest BinaryEncoder, raw []byte, builder Builder) error {
+	env := decodeEnvelope(raw)
+
+	// hard-expired (positive or negative): treat exactly like a cache miss
+	if !env.hard.IsZero() && c.getNow().After(env.hard) {
+		c.getMetrics().Track(CacheMiss)
+		return c.onCacheMiss(ctx, key, dest, builder)
+	}
+
+	if env.negative {
+		c.getMetrics().Track(CacheNegativeHit)
+		return ErrBuilderNotFound
+	}
+
+	if err := c.unmarshalOrInvalidate(ctx, key, dest, env.payload); err != nil {
+		return err
+	}
+
+	if !env.soft.IsZero() && c.getNow().After(env.soft) {
+		c.getMetrics().Track(CacheStaleServed)
+		c.refreshAsync(key, builder, dest)
+		return nil
+	}
 
+	c.getMetrics().Track(CacheHit)
 	return nil
 }
 
-func (c *Client) onCacheHit(ctx context.Context, key string, dest encoding.BinaryUnmarshaler, bytes []byte) error {
-	err := dest.UnmarshalBinary(bytes)
+// unmarshalOrInvalidate unmarshals payload into dest, invalidating key on failure so the
+// bad data isn't served again
+func (c *Client) unmarshalOrInvalidate(ctx context.Context, key string, dest encoding.BinaryUnmarshaler, payload []byte) error {
+	err := dest.UnmarshalBinary(payload)
 	if err != nil {
 		c.getLogger().Log("cache hit unmarshal error. key: '%s' error: %s", key, err)
 		c.getMetrics().Track(CacheUnmarshalError)
@@ -92,7 +288,6 @@ func (c *Client) onCacheHit(ctx context.Context, key string, dest encoding.Binar
 		return err
 	}
 
-	c.getMetrics().Track(CacheHit)
 	return nil
 }
 
@@ -104,10 +299,6 @@ func (c *Client) Set(ctx context.Context, key string, val encoding.BinaryMarshal
 		atomic.AddInt64(&c.pendingWrites, -1)
 	}()
 
-	// use independent context so we don't miss cache updated
-	ctx, cancelFn := context.WithTimeout(ctx, c.getWriteTimeout())
-	defer cancelFn()
-
 	bytes, err := val.MarshalBinary()
 	if err != nil {
 		c.getLogger().Log("cache update marshal error. key: '%s' error: %s", key, err)
@@ -115,13 +306,77 @@ func (c *Client) Set(ctx context.Context, key string, val encoding.BinaryMarshal
 		return
 	}
 
-	err = c.Storage.Set(ctx, key, bytes)
+	c.writeBytes(ctx, key, bytes)
+}
+
+// setBytes stores an already-marshaled payload, decrementing pendingWrites once done.
+// Used by onCacheMiss/refreshAsync to avoid marshaling dest twice when fanning a payload
+// out to followers.
+func (c *Client) setBytes(ctx context.Context, key string, payload []byte) {
+	defer func() {
+		// update tracking
+		atomic.AddInt64(&c.pendingWrites, -1)
+	}()
+
+	c.writeBytes(ctx, key, payload)
+}
+
+// writeBytes frames payload with the configured soft/hard expiry and persists it to Storage
+func (c *Client) writeBytes(ctx context.Context, key string, payload []byte) {
+	c.writeEnvelope(ctx, key, encodeEnvelope(payload, false, c.softExpiry(), c.hardExpiry()))
+}
+
+// setNegative stores a tombstone entry recording that builder reported key as not found,
+// decrementing pendingWrites once done
+func (c *Client) setNegative(ctx context.Context, key string) {
+	defer func() {
+		// update tracking
+		atomic.AddInt64(&c.pendingWrites, -1)
+	}()
+
+	c.writeEnvelope(ctx, key, encodeEnvelope(nil, true, time.Time{}, c.negativeExpiry()))
+}
+
+// writeEnvelope persists an already-framed envelope to Storage
+func (c *Client) writeEnvelope(ctx context.Context, key string, framed []byte) {
+	// use independent context so we don't miss cache updated
+	ctx, cancelFn := context.WithTimeout(ctx, c.getWriteTimeout())
+	defer cancelFn()
+
+	err := c.Storage.Set(ctx, key, framed)
 	if err != nil {
 		c.getLogger().Log("cache update set error. key: '%s' error: %s", key, err)
 		c.getMetrics().Track(CacheSetError)
 	}
 }
 
+// softExpiry returns the soft-TTL deadline for an entry written now, or the zero time.Time
+// when SoftTTL is disabled
+func (c *Client) softExpiry() time.Time {
+	if c.SoftTTL <= 0 {
+		return time.Time{}
+	}
+	return c.getNow().Add(c.SoftTTL)
+}
+
+// hardExpiry returns the hard-TTL deadline for an entry written now, or the zero time.Time
+// when HardTTL is disabled
+func (c *Client) hardExpiry() time.Time {
+	if c.HardTTL <= 0 {
+		return time.Time{}
+	}
+	return c.getNow().Add(c.HardTTL)
+}
+
+// negativeExpiry returns the deadline for a negative cache entry written now, or the zero
+// time.Time when NegativeTTL is disabled (in which case leadCall writes no tombstone)
+func (c *Client) negativeExpiry() time.Time {
+	if c.NegativeTTL <= 0 {
+		return time.Time{}
+	}
+	return c.getNow().Add(c.NegativeTTL)
+}
+
 // Invalidate will force invalidate any matching key in the cache
 func (c *Client) Invalidate(ctx context.Context, key string) error {
 	err := c.Storage.Invalidate(ctx, key)
@@ -152,6 +407,15 @@ func (c *Client) getMetrics() Metrics {
 	return noopMetrics
 }
 
+// return the current time, or the overridden clock supplied by tests
+func (c *Client) getNow() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+
+	return time.Now()
+}
+
 // return the timeout on cache writes
 func (c *Client) getWriteTimeout() time.Duration {
 	if int64(c.WriteTimeout) > 0 {
@@ -0,0 +1,100 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// envelopeMagic is the leading byte of every envelope written by this version of Client.
+// Bytes written by older clients (or any Storage.Set call made directly, bypassing Client)
+// won't have it, so decodeEnvelope falls back to treating them as a legacy, always-fresh
+// payload.
+const envelopeMagic byte = 0xc5
+
+const envelopeVersion byte = 1
+
+// envelope flag bits
+const envelopeFlagNegative byte = 1 << 0
+
+// envelopeHeaderLen is magic(1) + version(1) + flags(1) + softExpiry(8) + hardExpiry(8)
+const envelopeHeaderLen = 19
+
+// envelope is the decoded form of a value written to Storage by Client: the payload plus
+// the soft/hard expiry deadlines used for stale-while-revalidate and negative caching.
+type envelope struct {
+	payload  []byte
+	negative bool
+	soft     time.Time
+	hard     time.Time
+}
+
+// encodeEnvelope frames payload with soft/hard expiry deadlines (zero time.Time means "no
+// deadline") and the negative flag, ready to hand to Storage.Set.
+func encodeEnvelope(payload []byte, negative bool, soft, hard time.Time) []byte {
+	buf := make([]byte, envelopeHeaderLen+len(payload))
+
+	buf[0] = envelopeMagic
+	buf[1] = envelopeVersion
+
+	var flags byte
+	if negative {
+		flags |= envelopeFlagNegative
+	}
+	buf[2] = flags
+
+	binary.BigEndian.PutUint64(buf[3:11], uint64(unixNanoOf(soft)))
+	binary.BigEndian.PutUint64(buf[11:19], uint64(unixNanoOf(hard)))
+	copy(buf[envelopeHeaderLen:], payload)
+
+	return buf
+}
+
+// decodeEnvelope parses raw bytes previously written by encodeEnvelope. When raw doesn't
+// start with envelopeMagic (too short, or written before this Client framed its values) it
+// is treated as a legacy unframed payload with no expiry - i.e. always fresh, never negative.
+func decodeEnvelope(raw []byte) envelope {
+	if len(raw) < envelopeHeaderLen || raw[0] != envelopeMagic {
+		return envelope{payload: raw}
+	}
+
+	flags := raw[2]
+	soft := timeFromUnixNano(int64(binary.BigEndian.Uint64(raw[3:11])))
+	hard := timeFromUnixNano(int64(binary.BigEndian.Uint64(raw[11:19])))
+
+	return envelope{
+		payload:  raw[envelopeHeaderLen:],
+		negative: flags&envelopeFlagNegative != 0,
+		soft:     soft,
+		hard:     hard,
+	}
+}
+
+// unixNanoOf returns 0 for the zero time.Time, otherwise t.UnixNano()
+func unixNanoOf(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+// timeFromUnixNano is the inverse of unixNanoOf
+func timeFromUnixNano(nano int64) time.Time {
+	if nano == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nano)
+}
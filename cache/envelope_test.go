@@ -0,0 +1,70 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvelope_RoundTrip(t *testing.T) {
+	soft := time.Unix(1700000000, 0)
+	hard := time.Unix(1700000100, 0)
+
+	raw := encodeEnvelope([]byte("payload"), false, soft, hard)
+	env := decodeEnvelope(raw)
+
+	assert.Equal(t, []byte("payload"), env.payload)
+	assert.False(t, env.negative)
+	assert.True(t, soft.Equal(env.soft))
+	assert.True(t, hard.Equal(env.hard))
+}
+
+func TestEnvelope_ZeroDeadlinesRoundTripAsZeroTime(t *testing.T) {
+	raw := encodeEnvelope([]byte("payload"), false, time.Time{}, time.Time{})
+	env := decodeEnvelope(raw)
+
+	assert.True(t, env.soft.IsZero())
+	assert.True(t, env.hard.IsZero())
+}
+
+func TestEnvelope_NegativeFlagRoundTrips(t *testing.T) {
+	raw := encodeEnvelope(nil, true, time.Time{}, time.Unix(1700000100, 0))
+	env := decodeEnvelope(raw)
+
+	assert.True(t, env.negative)
+	assert.Empty(t, env.payload)
+}
+
+func TestEnvelope_LegacyUnframedBytesAreTreatedAsFreshPayload(t *testing.T) {
+	legacy := []byte("raw-value-written-before-envelopes-existed")
+
+	env := decodeEnvelope(legacy)
+
+	assert.Equal(t, legacy, env.payload)
+	assert.False(t, env.negative)
+	assert.True(t, env.soft.IsZero())
+	assert.True(t, env.hard.IsZero())
+}
+
+func TestEnvelope_ShortBytesAreTreatedAsLegacyPayload(t *testing.T) {
+	short := []byte{0xc5, 0x01}
+
+	env := decodeEnvelope(short)
+
+	assert.Equal(t, short, env.payload)
+}
@@ -0,0 +1,63 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrCacheMiss is returned by Storage.Get when the supplied key is not present in the cache
+var ErrCacheMiss = errors.New("cache: key not found")
+
+// ErrBuilderNotFound may be returned by a Builder to indicate that the key legitimately has
+// no value (as opposed to a transient build failure). Client responds by writing a
+// short-lived negative cache entry (see Client.NegativeTTL) so repeated lookups of the same
+// missing key don't repeatedly hit the Builder. Get returns this same sentinel to callers,
+// both on the initial miss and on subsequent negative-cache hits.
+var ErrBuilderNotFound = errors.New("cache: builder reported key not found")
+
+// LambdaError wraps an error returned by a Builder so that callers can distinguish build
+// failures from cache infrastructure failures
+type LambdaError struct {
+	Cause error
+}
+
+// Error implements error
+func (e *LambdaError) Error() string {
+	return fmt.Sprintf("cache: builder error: %s", e.Cause)
+}
+
+// Unwrap allows errors.Is/errors.As to reach the underlying builder error
+func (e *LambdaError) Unwrap() error {
+	return e.Cause
+}
+
+// MarshalError wraps an error returned by dest.MarshalBinary after a successful Builder
+// run. It is surfaced to singleflight followers, who have no dest of their own to have
+// populated via Build and so cannot be given a usable value when the leader's marshal fails.
+type MarshalError struct {
+	Cause error
+}
+
+// Error implements error
+func (e *MarshalError) Error() string {
+	return fmt.Sprintf("cache: marshal error: %s", e.Cause)
+}
+
+// Unwrap allows errors.Is/errors.As to reach the underlying marshal error
+func (e *MarshalError) Unwrap() error {
+	return e.Cause
+}
@@ -0,0 +1,240 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock is a controllable time source for deterministic SoftTTL/HardTTL/NegativeTTL tests
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(1600000000, 0)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// recordingMetrics captures every tracked event for assertions
+type recordingMetrics struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *recordingMetrics) Track(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+func (r *recordingMetrics) count(e Event) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := 0
+	for _, got := range r.events {
+		if got == e {
+			n++
+		}
+	}
+	return n
+}
+
+func TestClient_Get_StaleWhileRevalidate(t *testing.T) {
+	clock := newFakeClock()
+	metrics := &recordingMetrics{}
+	var buildCount int64
+
+	builder := BuilderFunc(func(ctx context.Context, key string, dest BinaryEncoder) error {
+		n := atomic.AddInt64(&buildCount, 1)
+		return dest.(*sfString).UnmarshalBinary([]byte(stringOfBuild(n)))
+	})
+
+	client := &Client{
+		Storage: newSFStorage(),
+		Metrics: metrics,
+		SoftTTL: 10 * time.Millisecond,
+		HardTTL: time.Hour,
+		now:     clock.Now,
+	}
+
+	// first Get: cold miss, builds synchronously
+	dest := &sfString{}
+	err := client.Get(context.Background(), "k", dest, builder)
+	assert.NoError(t, err)
+	assert.Equal(t, "build-1", dest.val)
+	assert.EqualValues(t, 1, atomic.LoadInt64(&buildCount))
+
+	// wait for the async Set from the first Get to land before advancing the clock, otherwise
+	// the next Get below is a genuine Storage miss rather than a stale hit
+	assert.Eventually(t, func() bool {
+		_, err := client.Storage.Get(context.Background(), "k")
+		return err == nil
+	}, time.Second, time.Millisecond)
+
+	// past SoftTTL but well within HardTTL: should serve the stale value immediately
+	clock.Advance(20 * time.Millisecond)
+
+	dest = &sfString{}
+	err = client.Get(context.Background(), "k", dest, builder)
+	assert.NoError(t, err)
+	assert.Equal(t, "build-1", dest.val, "stale value should be served without waiting on the refresh")
+	assert.Equal(t, 1, metrics.count(CacheStaleServed))
+
+	// the async refresh, and its async Set, should land shortly after
+	assert.Eventually(t, func() bool {
+		raw, err := client.Storage.Get(context.Background(), "k")
+		return err == nil && decodeEnvelope(raw).payload != nil && string(decodeEnvelope(raw).payload) == "build-2"
+	}, time.Second, time.Millisecond)
+	assert.EqualValues(t, 2, atomic.LoadInt64(&buildCount))
+
+	dest = &sfString{}
+	err = client.Get(context.Background(), "k", dest, builder)
+	assert.NoError(t, err)
+	assert.Equal(t, "build-2", dest.val, "subsequent Get should observe the refreshed value")
+}
+
+func TestClient_Get_HardTTLForcesRebuildRatherThanServingStale(t *testing.T) {
+	clock := newFakeClock()
+	metrics := &recordingMetrics{}
+	var buildCount int64
+
+	builder := BuilderFunc(func(ctx context.Context, key string, dest BinaryEncoder) error {
+		n := atomic.AddInt64(&buildCount, 1)
+		return dest.(*sfString).UnmarshalBinary([]byte(stringOfBuild(n)))
+	})
+
+	client := &Client{
+		Storage: newSFStorage(),
+		Metrics: metrics,
+		SoftTTL: 5 * time.Millisecond,
+		HardTTL: 10 * time.Millisecond,
+		now:     clock.Now,
+	}
+
+	err := client.Get(context.Background(), "k", &sfString{}, builder)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, atomic.LoadInt64(&buildCount))
+
+	// wait for the async Set to land so the next Get finds a hard-expired entry rather than
+	// a genuine Storage miss
+	assert.Eventually(t, func() bool {
+		_, err := client.Storage.Get(context.Background(), "k")
+		return err == nil
+	}, time.Second, time.Millisecond)
+
+	clock.Advance(20 * time.Millisecond)
+
+	dest := &sfString{}
+	err = client.Get(context.Background(), "k", dest, builder)
+	assert.NoError(t, err)
+	assert.Equal(t, "build-2", dest.val, "past HardTTL the entry should be rebuilt synchronously, not served stale")
+	assert.Equal(t, 0, metrics.count(CacheStaleServed))
+}
+
+func TestClient_Get_NegativeCachingShieldsBuilder(t *testing.T) {
+	clock := newFakeClock()
+	metrics := &recordingMetrics{}
+	var buildCount int64
+
+	builder := BuilderFunc(func(ctx context.Context, key string, dest BinaryEncoder) error {
+		atomic.AddInt64(&buildCount, 1)
+		return ErrBuilderNotFound
+	})
+
+	client := &Client{
+		Storage:     newSFStorage(),
+		Metrics:     metrics,
+		NegativeTTL: 50 * time.Millisecond,
+		now:         clock.Now,
+	}
+
+	err := client.Get(context.Background(), "missing", &sfString{}, builder)
+	assert.Equal(t, ErrBuilderNotFound, err)
+	assert.EqualValues(t, 1, atomic.LoadInt64(&buildCount))
+
+	// wait for the tombstone write to land directly via Storage - polling through Client.Get
+	// here would itself be a cache miss (re-invoking the builder) until the async setNegative
+	// completes, which made buildCount nondeterministic
+	assert.Eventually(t, func() bool {
+		_, err := client.Storage.Get(context.Background(), "missing")
+		return err == nil
+	}, time.Second, time.Millisecond)
+
+	err = client.Get(context.Background(), "missing", &sfString{}, builder)
+	assert.Equal(t, ErrBuilderNotFound, err)
+	assert.Equal(t, 1, metrics.count(CacheNegativeHit))
+	assert.EqualValues(t, 1, atomic.LoadInt64(&buildCount), "builder should not be invoked again while the tombstone is valid")
+}
+
+func TestClient_Get_NegativeCacheEntryExpires(t *testing.T) {
+	clock := newFakeClock()
+	var buildCount int64
+
+	builder := BuilderFunc(func(ctx context.Context, key string, dest BinaryEncoder) error {
+		atomic.AddInt64(&buildCount, 1)
+		return ErrBuilderNotFound
+	})
+
+	client := &Client{
+		Storage:     newSFStorage(),
+		NegativeTTL: 10 * time.Millisecond,
+		now:         clock.Now,
+	}
+
+	err := client.Get(context.Background(), "missing", &sfString{}, builder)
+	assert.Equal(t, ErrBuilderNotFound, err)
+
+	assert.Eventually(t, func() bool {
+		_, getErr := client.Storage.Get(context.Background(), "missing")
+		return getErr == nil
+	}, time.Second, time.Millisecond)
+
+	clock.Advance(20 * time.Millisecond)
+
+	err = client.Get(context.Background(), "missing", &sfString{}, builder)
+	assert.Equal(t, ErrBuilderNotFound, err)
+	assert.EqualValues(t, 2, atomic.LoadInt64(&buildCount), "expired tombstone should let the builder run again")
+}
+
+func stringOfBuild(n int64) string {
+	switch n {
+	case 1:
+		return "build-1"
+	case 2:
+		return "build-2"
+	default:
+		return "build-n"
+	}
+}
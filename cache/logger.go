@@ -0,0 +1,28 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+// Logger defines the interface used by cache.Client to report non-fatal errors
+type Logger interface {
+	// Log writes a formatted message, following fmt.Sprintf semantics
+	Log(format string, args ...interface{})
+}
+
+// noopLogger discards everything. Used when Client.Logger is not supplied
+var noopLogger Logger = noopLoggerT{}
+
+type noopLoggerT struct{}
+
+func (noopLoggerT) Log(format string, args ...interface{}) {}
@@ -0,0 +1,251 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package lru provides an in-process, thread-safe cache.Storage implementation with
+// bounded size and per-entry TTL, suitable as a default backend for tests and
+// single-node deployments.
+package lru
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/swamvenk/go-commons/cache"
+)
+
+// DefaultJanitorInterval is how often the background janitor sweeps expired entries
+// when one is enabled via Storage.StartJanitor
+const DefaultJanitorInterval = time.Minute
+
+// Storage is a thread-safe, in-memory implementation of cache.Storage backed by an LRU
+// eviction policy with optional max entries, max total bytes and per-entry TTL bounds.
+//
+// The zero value is not usable, construct with New.
+type Storage struct {
+	// maxEntries bounds the number of entries retained. Zero means unbounded.
+	maxEntries int
+
+	// maxBytes bounds the total size (in bytes) of all values retained. Zero means unbounded.
+	maxBytes int64
+
+	// ttl is applied to every entry on Set. Zero means entries never expire.
+	ttl time.Duration
+
+	mu        sync.Mutex
+	ll        *list.List
+	items     map[string]*list.Element
+	usedBytes int64
+}
+
+// entry is the value stored in each list.Element
+type entry struct {
+	key      string
+	val      []byte
+	expireAt time.Time
+}
+
+// Option configures a Storage created via New
+type Option func(*Storage)
+
+// WithMaxEntries bounds the number of entries the Storage will retain, evicting the least
+// recently used entry once the bound is exceeded
+func WithMaxEntries(max int) Option {
+	return func(s *Storage) {
+		s.maxEntries = max
+	}
+}
+
+// WithMaxBytes bounds the total size, in bytes, of all values the Storage will retain,
+// evicting the least recently used entries once the bound is exceeded
+func WithMaxBytes(max int64) Option {
+	return func(s *Storage) {
+		s.maxBytes = max
+	}
+}
+
+// WithTTL sets a per-entry time-to-live applied on Set. Expired entries are removed lazily
+// on Get (and by the janitor, if started) and reported as cache.ErrCacheMiss
+func WithTTL(ttl time.Duration) Option {
+	return func(s *Storage) {
+		s.ttl = ttl
+	}
+}
+
+// New creates a ready to use Storage. With no options the cache is unbounded and entries
+// never expire (equivalent to an LRU with no eviction pressure, which is rarely what you want).
+func New(opts ...Option) *Storage {
+	s := &Storage{
+		ll:    list.New(),
+		items: map[string]*list.Element{},
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Get implements cache.Storage
+func (s *Storage) Get(ctx context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.items[key]
+	if !ok {
+		return nil, cache.ErrCacheMiss
+	}
+
+	ent := elem.Value.(*entry)
+	if s.isExpired(ent) {
+		s.removeElement(elem)
+		return nil, cache.ErrCacheMiss
+	}
+
+	s.ll.MoveToFront(elem)
+
+	// return a copy so callers can't mutate our stored bytes
+	out := make([]byte, len(ent.val))
+	copy(out, ent.val)
+	return out, nil
+}
+
+// Set implements cache.Storage
+func (s *Storage) Set(ctx context.Context, key string, val []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := make([]byte, len(val))
+	copy(stored, val)
+
+	var expireAt time.Time
+	if s.ttl > 0 {
+		expireAt = time.Now().Add(s.ttl)
+	}
+
+	if elem, ok := s.items[key]; ok {
+		old := elem.Value.(*entry)
+		s.usedBytes -= int64(len(old.val))
+		old.val = stored
+		old.expireAt = expireAt
+		s.usedBytes += int64(len(stored))
+		s.ll.MoveToFront(elem)
+	} else {
+		elem := s.ll.PushFront(&entry{key: key, val: stored, expireAt: expireAt})
+		s.items[key] = elem
+		s.usedBytes += int64(len(stored))
+	}
+
+	s.evict()
+
+	return nil
+}
+
+// Invalidate implements cache.Storage
+func (s *Storage) Invalidate(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, ok := s.items[key]; ok {
+		s.removeElement(elem)
+	}
+
+	return nil
+}
+
+// StartJanitor launches a background goroutine that periodically sweeps expired entries so
+// that memory used by never-read, expired keys is reclaimed. Callers own the returned
+// stop function and must call it to release the goroutine.
+func (s *Storage) StartJanitor(interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		interval = DefaultJanitorInterval
+	}
+
+	stopCh := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.sweep()
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(stopCh)
+	}
+}
+
+// sweep removes all currently expired entries
+func (s *Storage) sweep() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for elem := s.ll.Back(); elem != nil; {
+		prev := elem.Prev()
+		if s.isExpired(elem.Value.(*entry)) {
+			s.removeElement(elem)
+		}
+		elem = prev
+	}
+}
+
+// evict removes entries from the back of the list until both bounds are satisfied. Caller
+// must hold s.mu.
+func (s *Storage) evict() {
+	for s.maxEntries > 0 && s.ll.Len() > s.maxEntries {
+		s.removeOldest()
+	}
+
+	for s.maxBytes > 0 && s.usedBytes > s.maxBytes && s.ll.Len() > 0 {
+		s.removeOldest()
+	}
+}
+
+// removeOldest evicts the least recently used entry. Caller must hold s.mu.
+func (s *Storage) removeOldest() {
+	elem := s.ll.Back()
+	if elem != nil {
+		s.removeElement(elem)
+	}
+}
+
+// removeElement unlinks elem from the list and index. Caller must hold s.mu.
+func (s *Storage) removeElement(elem *list.Element) {
+	ent := elem.Value.(*entry)
+	s.ll.Remove(elem)
+	delete(s.items, ent.key)
+	s.usedBytes -= int64(len(ent.val))
+}
+
+// isExpired reports whether ent is past its TTL
+func (s *Storage) isExpired(ent *entry) bool {
+	return !ent.expireAt.IsZero() && time.Now().After(ent.expireAt)
+}
+
+// Len returns the current number of entries, expired or not
+func (s *Storage) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.ll.Len()
+}
@@ -0,0 +1,126 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lru
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/swamvenk/go-commons/cache"
+)
+
+func TestStorage_GetSet(t *testing.T) {
+	s := New()
+	ctx := context.Background()
+
+	_, err := s.Get(ctx, "missing")
+	assert.Equal(t, cache.ErrCacheMiss, err)
+
+	err = s.Set(ctx, "k", []byte("v"))
+	assert.NoError(t, err)
+
+	val, err := s.Get(ctx, "k")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("v"), val)
+}
+
+func TestStorage_Invalidate(t *testing.T) {
+	s := New()
+	ctx := context.Background()
+
+	assert.NoError(t, s.Set(ctx, "k", []byte("v")))
+	assert.NoError(t, s.Invalidate(ctx, "k"))
+
+	_, err := s.Get(ctx, "k")
+	assert.Equal(t, cache.ErrCacheMiss, err)
+}
+
+func TestStorage_EvictsOnMaxEntries(t *testing.T) {
+	s := New(WithMaxEntries(2))
+	ctx := context.Background()
+
+	assert.NoError(t, s.Set(ctx, "a", []byte("1")))
+	assert.NoError(t, s.Set(ctx, "b", []byte("2")))
+	assert.NoError(t, s.Set(ctx, "c", []byte("3")))
+
+	assert.Equal(t, 2, s.Len())
+
+	_, err := s.Get(ctx, "a")
+	assert.Equal(t, cache.ErrCacheMiss, err, "oldest entry should have been evicted")
+
+	_, err = s.Get(ctx, "c")
+	assert.NoError(t, err)
+}
+
+func TestStorage_EvictsOnMaxBytes(t *testing.T) {
+	s := New(WithMaxBytes(2))
+	ctx := context.Background()
+
+	assert.NoError(t, s.Set(ctx, "a", []byte("1")))
+	assert.NoError(t, s.Set(ctx, "b", []byte("22")))
+
+	assert.Equal(t, 1, s.Len(), "adding 'b' should evict 'a' to respect the byte bound")
+
+	_, err := s.Get(ctx, "a")
+	assert.Equal(t, cache.ErrCacheMiss, err)
+}
+
+func TestStorage_GetPromotesToFront(t *testing.T) {
+	s := New(WithMaxEntries(2))
+	ctx := context.Background()
+
+	assert.NoError(t, s.Set(ctx, "a", []byte("1")))
+	assert.NoError(t, s.Set(ctx, "b", []byte("2")))
+
+	// touch "a" so "b" becomes the least recently used entry
+	_, err := s.Get(ctx, "a")
+	assert.NoError(t, err)
+
+	assert.NoError(t, s.Set(ctx, "c", []byte("3")))
+
+	_, err = s.Get(ctx, "b")
+	assert.Equal(t, cache.ErrCacheMiss, err)
+
+	_, err = s.Get(ctx, "a")
+	assert.NoError(t, err)
+}
+
+func TestStorage_TTLExpiresLazily(t *testing.T) {
+	s := New(WithTTL(10 * time.Millisecond))
+	ctx := context.Background()
+
+	assert.NoError(t, s.Set(ctx, "k", []byte("v")))
+
+	time.Sleep(20 * time.Millisecond)
+
+	_, err := s.Get(ctx, "k")
+	assert.Equal(t, cache.ErrCacheMiss, err)
+}
+
+func TestStorage_Janitor_SweepsExpiredEntries(t *testing.T) {
+	s := New(WithTTL(10 * time.Millisecond))
+	ctx := context.Background()
+
+	assert.NoError(t, s.Set(ctx, "k", []byte("v")))
+
+	stop := s.StartJanitor(5 * time.Millisecond)
+	defer stop()
+
+	assert.Eventually(t, func() bool {
+		return s.Len() == 0
+	}, time.Second, time.Millisecond, "janitor should remove the expired entry")
+}
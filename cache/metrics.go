@@ -0,0 +1,81 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import "time"
+
+// Event identifies the kind of cache event being tracked via Metrics
+type Event int
+
+// Event values tracked by cache.Client
+const (
+	// CacheHit indicates the key was found in Storage with a fresh, non-negative entry
+	CacheHit Event = iota
+
+	// CacheMiss indicates the key was not found in Storage, or its entry was past its hard
+	// expiry and is rebuilt as if it were absent
+	CacheMiss
+
+	// CacheGetError indicates Storage.Get returned an error other than ErrCacheMiss
+	CacheGetError
+
+	// CacheSetError indicates Storage.Set returned an error
+	CacheSetError
+
+	// CacheInvalidateError indicates Storage.Invalidate returned an error
+	CacheInvalidateError
+
+	// CacheLambdaError indicates the Builder returned an error on a cache miss or a
+	// background stale-while-revalidate refresh
+	CacheLambdaError
+
+	// CacheMarshalError indicates MarshalBinary failed while writing to the cache
+	CacheMarshalError
+
+	// CacheUnmarshalError indicates UnmarshalBinary failed while reading from the cache
+	CacheUnmarshalError
+
+	// CacheSingleflightShared indicates a Get was served from an in-flight Builder run
+	// triggered by another concurrent caller for the same key
+	CacheSingleflightShared
+
+	// CacheStaleServed indicates a Get returned an entry past its SoftTTL while an async
+	// refresh was kicked off in the background
+	CacheStaleServed
+
+	// CacheNegativeHit indicates a Get was served from a negative (tombstone) cache entry
+	// written after a Builder previously reported ErrBuilderNotFound
+	CacheNegativeHit
+)
+
+// Metrics allows callers to track cache events (hit/miss/etc)
+type Metrics interface {
+	// Track records the occurrence of the supplied event
+	Track(event Event)
+}
+
+// MetricsTimings is an optional extension of Metrics. When a Client's Metrics also
+// implements MetricsTimings, Client.Get reports how long each call took, hit or miss.
+type MetricsTimings interface {
+	// ObserveGetDuration records the wall-clock duration of a single Client.Get call
+	ObserveGetDuration(d time.Duration)
+}
+
+// noopMetrics discards everything. Used when Client.Metrics is not supplied
+var noopMetrics Metrics = noopMetricsT{}
+
+type noopMetricsT struct{}
+
+func (noopMetricsT) Track(event Event) {}
@@ -0,0 +1,154 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package prom provides a cache.Metrics implementation that reports events and Get
+// latency as Prometheus metrics, ready to be exposed via promhttp.Handler.
+package prom
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/swamvenk/go-commons/cache"
+)
+
+// DefaultSubsystem is used when Options.Subsystem is not set
+const DefaultSubsystem = "cache"
+
+// Metrics implements cache.Metrics and cache.MetricsTimings by recording to Prometheus
+// counters and a histogram.
+type Metrics struct {
+	events   *prometheus.CounterVec
+	duration prometheus.Histogram
+}
+
+// Options configures New
+type Options struct {
+	// Namespace is prefixed to every metric name (optional)
+	Namespace string
+
+	// Subsystem is prefixed to every metric name, defaulting to DefaultSubsystem
+	Subsystem string
+
+	// Registerer is used to register the collectors, defaulting to prometheus.DefaultRegisterer
+	Registerer prometheus.Registerer
+
+	// Buckets overrides the Get-duration histogram buckets, defaulting to prometheus.DefBuckets
+	Buckets []float64
+}
+
+// Option configures Options passed to New
+type Option func(*Options)
+
+// WithNamespace sets Options.Namespace
+func WithNamespace(namespace string) Option {
+	return func(o *Options) {
+		o.Namespace = namespace
+	}
+}
+
+// WithSubsystem sets Options.Subsystem
+func WithSubsystem(subsystem string) Option {
+	return func(o *Options) {
+		o.Subsystem = subsystem
+	}
+}
+
+// WithRegisterer sets Options.Registerer
+func WithRegisterer(reg prometheus.Registerer) Option {
+	return func(o *Options) {
+		o.Registerer = reg
+	}
+}
+
+// WithBuckets sets Options.Buckets
+func WithBuckets(buckets []float64) Option {
+	return func(o *Options) {
+		o.Buckets = buckets
+	}
+}
+
+// New creates a Metrics and registers its collectors with the configured Registerer.
+func New(opts ...Option) *Metrics {
+	o := &Options{
+		Subsystem:  DefaultSubsystem,
+		Registerer: prometheus.DefaultRegisterer,
+		Buckets:    prometheus.DefBuckets,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	m := &Metrics{
+		events: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: o.Namespace,
+			Subsystem: o.Subsystem,
+			Name:      "events_total",
+			Help:      "Count of cache.Client events by type",
+		}, []string{"event"}),
+
+		duration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: o.Namespace,
+			Subsystem: o.Subsystem,
+			Name:      "get_duration_seconds",
+			Help:      "Duration of cache.Client.Get calls, hit or miss",
+			Buckets:   o.Buckets,
+		}),
+	}
+
+	o.Registerer.MustRegister(m.events, m.duration)
+
+	return m
+}
+
+// Track implements cache.Metrics
+func (m *Metrics) Track(event cache.Event) {
+	m.events.WithLabelValues(eventLabel(event)).Inc()
+}
+
+// ObserveGetDuration implements cache.MetricsTimings
+func (m *Metrics) ObserveGetDuration(d time.Duration) {
+	m.duration.Observe(d.Seconds())
+}
+
+// eventLabel maps a cache.Event to its Prometheus label value
+func eventLabel(event cache.Event) string {
+	switch event {
+	case cache.CacheHit:
+		return "hit"
+	case cache.CacheMiss:
+		return "miss"
+	case cache.CacheGetError:
+		return "get_error"
+	case cache.CacheSetError:
+		return "set_error"
+	case cache.CacheInvalidateError:
+		return "invalidate_error"
+	case cache.CacheLambdaError:
+		return "lambda_error"
+	case cache.CacheMarshalError:
+		return "marshal_error"
+	case cache.CacheUnmarshalError:
+		return "unmarshal_error"
+	case cache.CacheSingleflightShared:
+		return "singleflight_shared"
+	case cache.CacheStaleServed:
+		return "stale_served"
+	case cache.CacheNegativeHit:
+		return "negative_hit"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prom
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swamvenk/go-commons/cache"
+)
+
+func TestMetrics_TrackIncrementsLabeledCounter(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := New(WithRegisterer(reg), WithNamespace("test"))
+
+	m.Track(cache.CacheHit)
+	m.Track(cache.CacheHit)
+	m.Track(cache.CacheMiss)
+	m.Track(cache.CacheStaleServed)
+	m.Track(cache.CacheNegativeHit)
+
+	families, err := reg.Gather()
+	require.NoError(t, err)
+
+	counts := map[string]float64{}
+	for _, family := range families {
+		if family.GetName() != "test_cache_events_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			counts[labelValue(metric, "event")] = metric.GetCounter().GetValue()
+		}
+	}
+
+	assert.Equal(t, 2.0, counts["hit"])
+	assert.Equal(t, 1.0, counts["miss"])
+	assert.Equal(t, 1.0, counts["stale_served"])
+	assert.Equal(t, 1.0, counts["negative_hit"])
+}
+
+func TestMetrics_ObserveGetDurationRecordsToHistogram(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := New(WithRegisterer(reg))
+
+	m.ObserveGetDuration(10 * time.Millisecond)
+
+	families, err := reg.Gather()
+	require.NoError(t, err)
+
+	var sampleCount uint64
+	for _, family := range families {
+		if family.GetName() != "cache_get_duration_seconds" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			sampleCount = metric.GetHistogram().GetSampleCount()
+		}
+	}
+
+	assert.EqualValues(t, 1, sampleCount)
+}
+
+func labelValue(metric *dto.Metric, name string) string {
+	for _, pair := range metric.GetLabel() {
+		if pair.GetName() == name {
+			return pair.GetValue()
+		}
+	}
+	return ""
+}
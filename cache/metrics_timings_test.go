@@ -0,0 +1,59 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// timingMetrics implements both Metrics and MetricsTimings
+type timingMetrics struct {
+	observed int64
+}
+
+func (t *timingMetrics) Track(event Event) {}
+
+func (t *timingMetrics) ObserveGetDuration(d time.Duration) {
+	atomic.AddInt64(&t.observed, 1)
+}
+
+func TestClient_Get_ReportsTimingsWhenSupported(t *testing.T) {
+	metrics := &timingMetrics{}
+	client := &Client{Storage: newSFStorage(), Metrics: metrics}
+
+	builder := BuilderFunc(func(ctx context.Context, key string, dest BinaryEncoder) error {
+		return dest.(*sfString).UnmarshalBinary([]byte("v"))
+	})
+
+	err := client.Get(context.Background(), "k", &sfString{}, builder)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, atomic.LoadInt64(&metrics.observed))
+}
+
+func TestClient_Get_PlainMetricsIsUnaffected(t *testing.T) {
+	client := &Client{Storage: newSFStorage()}
+
+	builder := BuilderFunc(func(ctx context.Context, key string, dest BinaryEncoder) error {
+		return dest.(*sfString).UnmarshalBinary([]byte("v"))
+	})
+
+	err := client.Get(context.Background(), "k", &sfString{}, builder)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,89 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package peers
+
+import (
+	"hash/crc32"
+	"sort"
+	"strconv"
+)
+
+// hashFunc hashes bytes onto the ring
+type hashFunc func(data []byte) uint32
+
+// hashRing implements consistent hashing over a set of peer names, each hashed onto the
+// ring `replicas` times (virtual nodes) to smooth out load distribution.
+type hashRing struct {
+	replicas int
+	hash     hashFunc
+
+	// sorted ring positions
+	keys []uint32
+
+	// ring position -> peer name
+	owners map[uint32]string
+}
+
+// newHashRing creates a hashRing with the supplied virtual node count. When fn is nil,
+// crc32.ChecksumIEEE is used.
+func newHashRing(replicas int, fn hashFunc) *hashRing {
+	if fn == nil {
+		fn = crc32.ChecksumIEEE
+	}
+
+	return &hashRing{
+		replicas: replicas,
+		hash:     fn,
+		owners:   map[uint32]string{},
+	}
+}
+
+// set replaces the ring's membership with peers
+func (r *hashRing) set(peerNames ...string) {
+	r.keys = r.keys[:0]
+	r.owners = make(map[uint32]string, len(peerNames)*r.replicas)
+
+	for _, peer := range peerNames {
+		for i := 0; i < r.replicas; i++ {
+			pos := r.hash([]byte(strconv.Itoa(i) + peer))
+			r.keys = append(r.keys, pos)
+			r.owners[pos] = peer
+		}
+	}
+
+	sort.Slice(r.keys, func(i, j int) bool { return r.keys[i] < r.keys[j] })
+}
+
+// isEmpty reports whether any peers are registered on the ring
+func (r *hashRing) isEmpty() bool {
+	return len(r.keys) == 0
+}
+
+// owner returns the peer responsible for key by hashing it and finding the next ring
+// position at or after it, wrapping around to the first position if necessary.
+func (r *hashRing) owner(key string) string {
+	if r.isEmpty() {
+		return ""
+	}
+
+	pos := r.hash([]byte(key))
+
+	idx := sort.Search(len(r.keys), func(i int) bool { return r.keys[i] >= pos })
+	if idx == len(r.keys) {
+		idx = 0
+	}
+
+	return r.owners[r.keys[idx]]
+}
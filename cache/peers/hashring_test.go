@@ -0,0 +1,89 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package peers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashRing_OwnerIsStableAcrossCalls(t *testing.T) {
+	r := newHashRing(50, nil)
+	r.set("peer-a", "peer-b", "peer-c")
+
+	key := "some-key"
+	first := r.owner(key)
+	assert.NotEmpty(t, first)
+
+	for i := 0; i < 100; i++ {
+		assert.Equal(t, first, r.owner(key))
+	}
+}
+
+func TestHashRing_EmptyRingHasNoOwner(t *testing.T) {
+	r := newHashRing(50, nil)
+	assert.Equal(t, "", r.owner("k"))
+}
+
+func TestHashRing_DistributesKeysAcrossPeers(t *testing.T) {
+	r := newHashRing(50, nil)
+	peers := []string{"peer-a", "peer-b", "peer-c", "peer-d"}
+	r.set(peers...)
+
+	counts := map[string]int{}
+	const totalKeys = 10000
+	for i := 0; i < totalKeys; i++ {
+		owner := r.owner(fmt.Sprintf("key-%d", i))
+		counts[owner]++
+	}
+
+	assert.Len(t, counts, len(peers), "every peer should own at least one key")
+
+	// with 50 virtual nodes per peer, distribution should be roughly even
+	expected := totalKeys / len(peers)
+	for peer, count := range counts {
+		assert.InDeltaf(t, expected, count, float64(expected)*0.3,
+			"peer %q got %d keys, expected around %d", peer, count, expected)
+	}
+}
+
+func TestHashRing_MostKeysStayOnRemovalOfOnePeer(t *testing.T) {
+	r := newHashRing(50, nil)
+	before := []string{"peer-a", "peer-b", "peer-c", "peer-d"}
+	r.set(before...)
+
+	const totalKeys = 5000
+	owners := make(map[string]string, totalKeys)
+	for i := 0; i < totalKeys; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		owners[key] = r.owner(key)
+	}
+
+	after := []string{"peer-a", "peer-b", "peer-c"}
+	r.set(after...)
+
+	moved := 0
+	for key, owner := range owners {
+		if r.owner(key) != owner {
+			moved++
+		}
+	}
+
+	// consistent hashing should only reshuffle keys owned by the removed peer (~1/4 here),
+	// not the whole keyspace
+	assert.Less(t, moved, totalKeys/2)
+}
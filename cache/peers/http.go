@@ -0,0 +1,108 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package peers
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/swamvenk/go-commons/cache"
+)
+
+// Handler returns an http.Handler that peers mount at their basePath to serve this node's
+// local shard. It never consults the ring - it only ever reads/writes p.local, so it is
+// safe to serve requests that another node's ring decided belong to us.
+func (p *PeerPool) Handler() http.Handler {
+	return http.HandlerFunc(p.serveHTTP)
+}
+
+func (p *PeerPool) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	key, ok := p.keyFromPath(r.URL.EscapedPath())
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		p.handleGet(w, r, key)
+	case http.MethodPut:
+		p.handleSet(w, r, key)
+	case http.MethodDelete:
+		p.handleInvalidate(w, r, key)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (p *PeerPool) handleGet(w http.ResponseWriter, r *http.Request, key string) {
+	val, err := p.local.Get(r.Context(), key)
+	if err == cache.ErrCacheMiss {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	_, _ = w.Write(val)
+}
+
+func (p *PeerPool) handleSet(w http.ResponseWriter, r *http.Request, key string) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := p.local.Set(r.Context(), key, body); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (p *PeerPool) handleInvalidate(w http.ResponseWriter, r *http.Request, key string) {
+	if err := p.local.Invalidate(r.Context(), key); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// keyFromPath extracts the URL-escaped key trailing p.basePath
+func (p *PeerPool) keyFromPath(path string) (string, bool) {
+	if !strings.HasPrefix(path, p.basePath) {
+		return "", false
+	}
+
+	escaped := strings.TrimPrefix(path, p.basePath)
+	if escaped == "" {
+		return "", false
+	}
+
+	key, err := url.PathUnescape(escaped)
+	if err != nil {
+		return "", false
+	}
+
+	return key, true
+}
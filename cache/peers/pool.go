@@ -0,0 +1,233 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package peers lets multiple processes cooperate as a distributed cache.Storage tier,
+// routing each key to an owning peer via consistent hashing (in the spirit of groupcache).
+package peers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/swamvenk/go-commons/cache"
+)
+
+// DefaultBasePath is prefixed to every path served/requested by a PeerPool's HTTP handler
+const DefaultBasePath = "/_cache/"
+
+// DefaultReplicas is the number of virtual nodes hashed onto the ring per peer
+const DefaultReplicas = 50
+
+// PeerPool implements cache.Storage by routing Get/Set to whichever peer (this node
+// included) owns a key according to a consistent-hash ring over the peer set.
+//
+// Construct with New and register peers with SetPeers before use.
+type PeerPool struct {
+	self     string
+	basePath string
+	local    cache.Storage
+	client   *http.Client
+
+	mu   sync.RWMutex
+	ring *hashRing
+}
+
+// Option configures a PeerPool created via New
+type Option func(*PeerPool)
+
+// WithBasePath overrides DefaultBasePath
+func WithBasePath(basePath string) Option {
+	return func(p *PeerPool) {
+		p.basePath = basePath
+	}
+}
+
+// WithReplicas overrides DefaultReplicas
+func WithReplicas(replicas int) Option {
+	return func(p *PeerPool) {
+		p.ring = newHashRing(replicas, nil)
+	}
+}
+
+// WithHTTPClient overrides the http.Client used to fetch values from remote peers
+func WithHTTPClient(client *http.Client) Option {
+	return func(p *PeerPool) {
+		p.client = client
+	}
+}
+
+// New creates a PeerPool for the node reachable at self (e.g. "http://10.0.0.1:8080"),
+// delegating to local for keys this node owns.
+func New(self string, local cache.Storage, opts ...Option) *PeerPool {
+	p := &PeerPool{
+		self:     self,
+		basePath: DefaultBasePath,
+		local:    local,
+		client:   http.DefaultClient,
+		ring:     newHashRing(DefaultReplicas, nil),
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	p.SetPeers(nil)
+
+	return p
+}
+
+// SetPeers atomically replaces the peer set (this node's own URL is always included) and
+// rebuilds the consistent-hash ring
+func (p *PeerPool) SetPeers(peerURLs []string) {
+	all := make([]string, 0, len(peerURLs)+1)
+	all = append(all, p.self)
+	all = append(all, peerURLs...)
+
+	ring := newHashRing(p.ringReplicas(), nil)
+	ring.set(all...)
+
+	p.mu.Lock()
+	p.ring = ring
+	p.mu.Unlock()
+}
+
+// ringReplicas returns the virtual node count configured on the current ring, or the
+// default if the ring hasn't been built yet
+func (p *PeerPool) ringReplicas() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.ring == nil {
+		return DefaultReplicas
+	}
+	return p.ring.replicas
+}
+
+// owner returns the URL of the peer (possibly this node) that owns key
+func (p *PeerPool) owner(key string) string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.ring.owner(key)
+}
+
+// Get implements cache.Storage, routing to the owning peer
+func (p *PeerPool) Get(ctx context.Context, key string) ([]byte, error) {
+	owner := p.owner(key)
+
+	if owner == "" || owner == p.self {
+		return p.local.Get(ctx, key)
+	}
+
+	return p.getFromPeer(ctx, owner, key)
+}
+
+// Set implements cache.Storage, routing to the owning peer
+func (p *PeerPool) Set(ctx context.Context, key string, val []byte) error {
+	owner := p.owner(key)
+
+	if owner == "" || owner == p.self {
+		return p.local.Set(ctx, key, val)
+	}
+
+	return p.setOnPeer(ctx, owner, key, val)
+}
+
+// Invalidate implements cache.Storage, routing to the owning peer
+func (p *PeerPool) Invalidate(ctx context.Context, key string) error {
+	owner := p.owner(key)
+
+	if owner == "" || owner == p.self {
+		return p.local.Invalidate(ctx, key)
+	}
+
+	return p.invalidateOnPeer(ctx, owner, key)
+}
+
+// getFromPeer issues an HTTP GET for key against peer's shard handler
+func (p *PeerPool) getFromPeer(ctx context.Context, peer, key string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, p.peerURL(peer, key), nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, cache.ErrCacheMiss
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("peers: peer %q returned status %d for key %q", peer, resp.StatusCode, key)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+// setOnPeer issues an HTTP PUT for key against peer's shard handler
+func (p *PeerPool) setOnPeer(ctx context.Context, peer, key string, val []byte) error {
+	req, err := http.NewRequest(http.MethodPut, p.peerURL(peer, key), bytes.NewReader(val))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("peers: peer %q returned status %d setting key %q", peer, resp.StatusCode, key)
+	}
+
+	return nil
+}
+
+// invalidateOnPeer issues an HTTP DELETE for key against peer's shard handler
+func (p *PeerPool) invalidateOnPeer(ctx context.Context, peer, key string) error {
+	req, err := http.NewRequest(http.MethodDelete, p.peerURL(peer, key), nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("peers: peer %q returned status %d invalidating key %q", peer, resp.StatusCode, key)
+	}
+
+	return nil
+}
+
+// peerURL builds the shard URL for key on peer
+func (p *PeerPool) peerURL(peer, key string) string {
+	return peer + p.basePath + url.PathEscape(key)
+}
@@ -0,0 +1,132 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package peers
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/swamvenk/go-commons/cache"
+	"github.com/swamvenk/go-commons/cache/lru"
+)
+
+// newTestNode spins up an httptest.Server backed by its own local LRU shard and the
+// PeerPool serving it, returning the pool and its server (which the caller must Close)
+func newTestNode(t *testing.T) (*PeerPool, *httptest.Server) {
+	t.Helper()
+
+	local := lru.New()
+	srv := httptest.NewUnstartedServer(nil)
+
+	pool := New("", local)
+	srv.Config.Handler = pool.Handler()
+	srv.Start()
+
+	pool.self = srv.URL
+	pool.SetPeers(nil)
+
+	return pool, srv
+}
+
+func TestPeerPool_LocalRoundTrip(t *testing.T) {
+	pool, srv := newTestNode(t)
+	defer srv.Close()
+
+	ctx := context.Background()
+
+	err := pool.Set(ctx, "k", []byte("v"))
+	assert.NoError(t, err)
+
+	val, err := pool.Get(ctx, "k")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("v"), val)
+}
+
+func TestPeerPool_LocalMiss(t *testing.T) {
+	pool, srv := newTestNode(t)
+	defer srv.Close()
+
+	_, err := pool.Get(context.Background(), "missing")
+	assert.Equal(t, cache.ErrCacheMiss, err)
+}
+
+func TestPeerPool_RoutesToOwningPeer(t *testing.T) {
+	poolA, srvA := newTestNode(t)
+	defer srvA.Close()
+
+	poolB, srvB := newTestNode(t)
+	defer srvB.Close()
+
+	poolA.SetPeers([]string{srvB.URL})
+	poolB.SetPeers([]string{srvA.URL})
+
+	ctx := context.Background()
+
+	// find a key pool A considers owned by B so we exercise the HTTP path
+	var remoteKey string
+	for i := 0; i < 1000; i++ {
+		key := "key-" + string(rune('a'+i%26)) + "-" + string(rune('0'+i/26))
+		if poolA.owner(key) != poolA.self {
+			remoteKey = key
+			break
+		}
+	}
+	assert.NotEmpty(t, remoteKey, "expected at least one key owned by the other peer")
+
+	err := poolA.Set(ctx, remoteKey, []byte("remote-value"))
+	assert.NoError(t, err)
+
+	// the value should have landed on B's local storage, not A's
+	val, err := poolB.local.Get(ctx, remoteKey)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("remote-value"), val)
+
+	// and A should be able to read it back through the pool
+	val, err = poolA.Get(ctx, remoteKey)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("remote-value"), val)
+}
+
+func TestPeerPool_Invalidate(t *testing.T) {
+	pool, srv := newTestNode(t)
+	defer srv.Close()
+
+	ctx := context.Background()
+	assert.NoError(t, pool.Set(ctx, "k", []byte("v")))
+	assert.NoError(t, pool.Invalidate(ctx, "k"))
+
+	_, err := pool.Get(ctx, "k")
+	assert.Equal(t, cache.ErrCacheMiss, err)
+}
+
+func TestPeerPool_SetPeersIsSafeForConcurrentUse(t *testing.T) {
+	pool, srv := newTestNode(t)
+	defer srv.Close()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			pool.SetPeers([]string{"http://peer-x"})
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		_, _ = pool.Get(context.Background(), "k")
+	}
+	<-done
+}
@@ -0,0 +1,234 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// sfString is a trivial BinaryEncoder used to exercise Get/singleflight
+type sfString struct {
+	val string
+}
+
+func (s *sfString) MarshalBinary() ([]byte, error) {
+	return []byte(s.val), nil
+}
+
+func (s *sfString) UnmarshalBinary(data []byte) error {
+	s.val = string(data)
+	return nil
+}
+
+// sfFailMarshalString behaves like sfString but always fails to marshal, used to exercise
+// what singleflight followers see when the leader's post-Build marshal errors
+type sfFailMarshalString struct {
+	val string
+}
+
+func (s *sfFailMarshalString) MarshalBinary() ([]byte, error) {
+	return nil, errors.New("sfFailMarshalString: marshal always fails")
+}
+
+func (s *sfFailMarshalString) UnmarshalBinary(data []byte) error {
+	s.val = string(data)
+	return nil
+}
+
+// sfStorage is a minimal in-memory Storage that always misses unless primed
+type sfStorage struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func newSFStorage() *sfStorage {
+	return &sfStorage{data: map[string][]byte{}}
+}
+
+func (s *sfStorage) Get(ctx context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	val, ok := s.data[key]
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+	return val, nil
+}
+
+func (s *sfStorage) Set(ctx context.Context, key string, val []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data[key] = val
+	return nil
+}
+
+func (s *sfStorage) Invalidate(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.data, key)
+	return nil
+}
+
+func TestClient_Get_SingleflightDedupesConcurrentBuilds(t *testing.T) {
+	var buildCount int64
+
+	builder := BuilderFunc(func(ctx context.Context, key string, dest BinaryEncoder) error {
+		atomic.AddInt64(&buildCount, 1)
+		time.Sleep(50 * time.Millisecond)
+		return dest.(*sfString).UnmarshalBinary([]byte("built-value"))
+	})
+
+	client := &Client{Storage: newSFStorage()}
+
+	const callers = 20
+	var wg sync.WaitGroup
+	results := make([]string, callers)
+	errs := make([]error, callers)
+
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			dest := &sfString{}
+			errs[i] = client.Get(context.Background(), "shared-key", dest, builder)
+			results[i] = dest.val
+		}(i)
+	}
+	wg.Wait()
+
+	assert.EqualValues(t, 1, atomic.LoadInt64(&buildCount))
+	for i := 0; i < callers; i++ {
+		assert.NoError(t, errs[i])
+		assert.Equal(t, "built-value", results[i])
+	}
+}
+
+func TestClient_Get_SingleflightPropagatesBuildError(t *testing.T) {
+	buildErr := errors.New("boom")
+
+	builder := BuilderFunc(func(ctx context.Context, key string, dest BinaryEncoder) error {
+		time.Sleep(20 * time.Millisecond)
+		return buildErr
+	})
+
+	client := &Client{Storage: newSFStorage()}
+
+	const callers = 5
+	var wg sync.WaitGroup
+	errs := make([]error, callers)
+
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = client.Get(context.Background(), "err-key", &sfString{}, builder)
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < callers; i++ {
+		assert.Error(t, errs[i])
+
+		var lambdaErr *LambdaError
+		assert.True(t, errors.As(errs[i], &lambdaErr))
+	}
+}
+
+func TestClient_Get_SingleflightFollowerGetsErrorOnLeaderMarshalFailure(t *testing.T) {
+	builder := BuilderFunc(func(ctx context.Context, key string, dest BinaryEncoder) error {
+		time.Sleep(30 * time.Millisecond)
+		return dest.(*sfFailMarshalString).UnmarshalBinary([]byte("built-value"))
+	})
+
+	client := &Client{Storage: newSFStorage()}
+
+	const callers = 5
+	var wg sync.WaitGroup
+	dests := make([]*sfFailMarshalString, callers)
+	errs := make([]error, callers)
+
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			dest := &sfFailMarshalString{}
+			errs[i] = client.Get(context.Background(), "marshal-fail-key", dest, builder)
+			dests[i] = dest
+		}(i)
+	}
+	wg.Wait()
+
+	var leaders, followers int
+	for i := 0; i < callers; i++ {
+		if errs[i] == nil {
+			leaders++
+			// the leader's dest was populated directly by Build, so it must be unaffected
+			// by the marshal failure that happens afterwards
+			assert.Equal(t, "built-value", dests[i].val)
+			continue
+		}
+
+		followers++
+		var marshalErr *MarshalError
+		assert.True(t, errors.As(errs[i], &marshalErr), "follower should get a MarshalError, not a silently empty value")
+		assert.Empty(t, dests[i].val, "follower dest should remain untouched when the leader's marshal fails")
+	}
+
+	assert.Equal(t, 1, leaders, "exactly one goroutine should be the singleflight leader")
+	assert.Equal(t, callers-1, followers)
+}
+
+func TestClient_Get_SingleflightReleasesInflightEntryAfterCompletion(t *testing.T) {
+	var buildCount int64
+
+	builder := BuilderFunc(func(ctx context.Context, key string, dest BinaryEncoder) error {
+		atomic.AddInt64(&buildCount, 1)
+		return dest.(*sfString).UnmarshalBinary([]byte("v"))
+	})
+
+	storage := newSFStorage()
+	client := &Client{Storage: storage}
+
+	err := client.Get(context.Background(), "k", &sfString{}, builder)
+	assert.NoError(t, err)
+
+	// wait for the async Set to land so the second Get is a genuine cache hit, not a second build
+	assert.Eventually(t, func() bool {
+		_, err := storage.Get(context.Background(), "k")
+		return err == nil
+	}, time.Second, time.Millisecond)
+
+	err = client.Get(context.Background(), "k", &sfString{}, builder)
+	assert.NoError(t, err)
+
+	assert.EqualValues(t, 1, atomic.LoadInt64(&buildCount))
+
+	// inflight map must not retain entries once calls complete
+	client.mu.Lock()
+	assert.Len(t, client.inflight, 0)
+	client.mu.Unlock()
+}
@@ -0,0 +1,31 @@
+// Copyright 2017 Corey Scott http://www.sage42.org/
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import "context"
+
+// Storage defines the backend a cache.Client persists to and reads from.
+//
+// Implementations must be safe for concurrent use.
+type Storage interface {
+	// Get returns the raw bytes stored for key, or ErrCacheMiss if absent/expired
+	Get(ctx context.Context, key string) ([]byte, error)
+
+	// Set stores the raw bytes for key
+	Set(ctx context.Context, key string, val []byte) error
+
+	// Invalidate removes any value stored for key
+	Invalidate(ctx context.Context, key string) error
+}